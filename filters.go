@@ -0,0 +1,243 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// stringSliceFlag implements flag.Value for a flag that can be repeated on
+// the command line, e.g. -exclude a -exclude b.
+type stringSliceFlag []string
+
+func (s *stringSliceFlag) String() string { return strings.Join(*s, ",") }
+
+func (s *stringSliceFlag) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// fileFilter controls which files and directories walkFiles visits.
+type fileFilter struct {
+	includes       []string
+	excludes       []string
+	excludeDirs    []string
+	followSymlinks bool
+	maxFileSize    int64
+}
+
+// excludesDir reports whether a directory with this base name should be
+// pruned from the walk entirely.
+func (f fileFilter) excludesDir(name string) bool {
+	for _, pattern := range f.excludeDirs {
+		if name == pattern {
+			return true
+		}
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// allows reports whether a file should be considered for analysis, after
+// exclude/include glob filtering (excludes win; with no includes, everything
+// not excluded is allowed).
+func (f fileFilter) allows(relPath, base string) bool {
+	for _, pattern := range f.excludes {
+		if globMatches(pattern, relPath, base) {
+			return false
+		}
+	}
+	if len(f.includes) == 0 {
+		return true
+	}
+	for _, pattern := range f.includes {
+		if globMatches(pattern, relPath, base) {
+			return true
+		}
+	}
+	return false
+}
+
+func globMatches(pattern, relPath, base string) bool {
+	if matched, _ := filepath.Match(pattern, base); matched {
+		return true
+	}
+	matched, _ := filepath.Match(pattern, relPath)
+	return matched
+}
+
+// gitignorePattern is one parsed line of a .gitignore file.
+type gitignorePattern struct {
+	raw     string
+	negate  bool
+	dirOnly bool
+}
+
+// gitignoreMatcher is the parsed .gitignore found in one directory; its
+// patterns are matched against paths relative to dir.
+type gitignoreMatcher struct {
+	dir      string
+	patterns []gitignorePattern
+}
+
+// loadGitignore parses dir/.gitignore, returning (nil, nil) if it doesn't exist.
+func loadGitignore(dir string) (*gitignoreMatcher, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	gm := &gitignoreMatcher{dir: dir}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		p := gitignorePattern{}
+		if strings.HasPrefix(trimmed, "!") {
+			p.negate = true
+			trimmed = trimmed[1:]
+		}
+		if strings.HasSuffix(trimmed, "/") {
+			p.dirOnly = true
+			trimmed = strings.TrimSuffix(trimmed, "/")
+		}
+		p.raw = trimmed
+		gm.patterns = append(gm.patterns, p)
+	}
+	return gm, nil
+}
+
+// matches reports whether this pattern matches relToDir, a slash-separated
+// path relative to the .gitignore's own directory. A pattern with no slash
+// (besides a trailing one already stripped) matches by basename at any
+// depth; one with a slash is anchored to the gitignore's directory. This
+// covers the common cases but, unlike real git, doesn't support "**".
+func (p gitignorePattern) matches(relToDir string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+
+	pat := strings.TrimPrefix(p.raw, "/")
+	anchored := strings.HasPrefix(p.raw, "/") || strings.Contains(pat, "/")
+	if anchored {
+		matched, _ := filepath.Match(pat, relToDir)
+		return matched
+	}
+
+	base := relToDir
+	if idx := strings.LastIndex(relToDir, "/"); idx >= 0 {
+		base = relToDir[idx+1:]
+	}
+	matched, _ := filepath.Match(pat, base)
+	return matched
+}
+
+// ignoreMatches applies git's semantics across a stack of .gitignore files
+// from outermost to innermost directory: the last pattern to match wins,
+// and a "!"-negated match un-ignores a path.
+func ignoreMatches(chain []*gitignoreMatcher, path string, isDir bool) bool {
+	ignored := false
+	for _, gm := range chain {
+		rel, err := filepath.Rel(gm.dir, path)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		for _, p := range gm.patterns {
+			if p.matches(rel, isDir) {
+				ignored = !p.negate
+			}
+		}
+	}
+	return ignored
+}
+
+// walkFiles is the producer: it walks dir, honoring filter and any
+// .gitignore files encountered, resolves each remaining file against
+// registry, and emits the matches into filesCh, closing it when done.
+func walkFiles(dir string, registry LanguageRegistry, filter fileFilter, filesCh chan<- sourceFile) error {
+	defer close(filesCh)
+	visited := map[string]bool{}
+	return walkDir(dir, dir, nil, filter, visited, registry, filesCh)
+}
+
+// walkDir recurses into dir by hand rather than using filepath.Walk, so it
+// can prune excluded/ignored directories before descending, optionally
+// follow symlinks without looping, and thread a growing .gitignore chain
+// down to descendants.
+func walkDir(root, dir string, ignoreChain []*gitignoreMatcher, filter fileFilter, visited map[string]bool, registry LanguageRegistry, filesCh chan<- sourceFile) error {
+	if real, err := filepath.EvalSymlinks(dir); err == nil {
+		if visited[real] {
+			return nil
+		}
+		visited[real] = true
+	}
+
+	if gm, err := loadGitignore(dir); err == nil && gm != nil {
+		ignoreChain = append(ignoreChain, gm)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			if !filter.followSymlinks {
+				continue
+			}
+			resolved, err := os.Stat(path)
+			if err != nil {
+				continue // broken symlink
+			}
+			info = resolved
+		}
+
+		if info.IsDir() {
+			if filter.excludesDir(entry.Name()) || ignoreMatches(ignoreChain, path, true) {
+				continue
+			}
+			if err := walkDir(root, path, ignoreChain, filter, visited, registry, filesCh); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if ignoreMatches(ignoreChain, path, false) {
+			continue
+		}
+
+		relPath, _ := filepath.Rel(root, path)
+		relPath = filepath.ToSlash(relPath)
+		if !filter.allows(relPath, entry.Name()) {
+			continue
+		}
+		if filter.maxFileSize > 0 && info.Size() > filter.maxFileSize {
+			continue
+		}
+
+		if spec, ok := registry.Lookup(path); ok {
+			filesCh <- sourceFile{path: path, spec: spec}
+		}
+	}
+
+	return nil
+}
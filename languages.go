@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CommentDelim is a pair of start/end tokens for a block comment. Start and
+// End are the same token for delimiters that aren't really "comments" but
+// are tracked the same way, such as Python's triple-quoted strings.
+type CommentDelim struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// LanguageSpec describes everything the tokenizer needs to know about one
+// language: how its comments, strings and line continuations are written,
+// and which files belong to it.
+type LanguageSpec struct {
+	Name                string         `json:"name"`
+	Extensions          []string       `json:"extensions"`
+	Filenames           []string       `json:"filenames"`
+	LineComments        []string       `json:"line_comments"`
+	BlockComments       []CommentDelim `json:"block_comments"`
+	NestedBlockComments bool           `json:"nested_block_comments"`
+	StringDelimiters    []string       `json:"string_delimiters"`
+	EscapeCharacter     string         `json:"escape_character"`
+	LineContinuation    string         `json:"line_continuation"`
+
+	// CommentBackslashContinuation is true for languages (C/C++) where a
+	// backslash-newline splice happens before comments are even parsed, so a
+	// "//" comment ending in "\" really does continue onto the next line.
+	// It's false elsewhere; Python/Shell's own "\" statement continuation
+	// doesn't extend a "#" comment onto the next line.
+	CommentBackslashContinuation bool `json:"comment_backslash_continuation"`
+
+	// RawStringPrefix introduces a C++11-style raw string literal, e.g. `R"`
+	// for `R"delim(...)delim"`: the text between the prefix and the first
+	// "(" is a caller-chosen delimiter, and nothing inside the raw string
+	// (comment markers, quotes, backslashes) is interpreted.
+	RawStringPrefix string `json:"raw_string_prefix"`
+
+	// DigitSeparator is a character (e.g. C++14's "'") that, when flanked by
+	// digits, is a digit-group separator rather than the start of a
+	// char/string literal sharing the same character.
+	DigitSeparator string `json:"digit_separator"`
+
+	// Trigraphs enables translation of legacy C trigraph sequences (e.g.
+	// "??/" for "\") before the line is tokenized.
+	Trigraphs bool `json:"trigraphs"`
+}
+
+// LanguageRegistry is the set of languages the tool knows how to analyze,
+// used to dispatch each file to its LanguageSpec by name or extension.
+type LanguageRegistry struct {
+	specs []LanguageSpec
+}
+
+// NewLanguageRegistry builds a registry from an explicit list of specs, in
+// the order they should be matched against a file.
+func NewLanguageRegistry(specs []LanguageSpec) LanguageRegistry {
+	return LanguageRegistry{specs: specs}
+}
+
+// DefaultLanguageRegistry returns the tool's built-in language set.
+func DefaultLanguageRegistry() LanguageRegistry {
+	return NewLanguageRegistry(defaultLanguageSpecs())
+}
+
+// LoadLanguageRegistry reads a language registry from a JSON or YAML config
+// file, chosen by the file's extension.
+func LoadLanguageRegistry(path string) (LanguageRegistry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return LanguageRegistry{}, fmt.Errorf("error reading language config %s: %w", path, err)
+	}
+
+	var specs []LanguageSpec
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		if err := json.Unmarshal(data, &specs); err != nil {
+			return LanguageRegistry{}, fmt.Errorf("error parsing language config %s: %w", path, err)
+		}
+	case ".yaml", ".yml":
+		specs, err = parseLanguagesYAML(data)
+		if err != nil {
+			return LanguageRegistry{}, fmt.Errorf("error parsing language config %s: %w", path, err)
+		}
+	default:
+		return LanguageRegistry{}, fmt.Errorf("error: unsupported language config format %q (use .json, .yaml, or .yml)", ext)
+	}
+
+	return NewLanguageRegistry(specs), nil
+}
+
+// Lookup finds the LanguageSpec for filename, matching filename globs (for
+// things like Makefile or Dockerfile) before falling back to extension.
+func (r LanguageRegistry) Lookup(filename string) (LanguageSpec, bool) {
+	base := filepath.Base(filename)
+
+	for _, spec := range r.specs {
+		for _, pattern := range spec.Filenames {
+			if matched, _ := filepath.Match(pattern, base); matched {
+				return spec, true
+			}
+		}
+	}
+
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext != "" {
+		for _, spec := range r.specs {
+			for _, specExt := range spec.Extensions {
+				if ext == specExt {
+					return spec, true
+				}
+			}
+		}
+	}
+
+	return LanguageSpec{}, false
+}
+
+// defaultLanguageSpecs is the built-in registry used when -langs isn't set.
+func defaultLanguageSpecs() []LanguageSpec {
+	return []LanguageSpec{
+		{
+			Name:                         "C/C++",
+			Extensions:                   []string{".c", ".cpp", ".cc", ".h", ".hpp"},
+			LineComments:                 []string{"//"},
+			BlockComments:                []CommentDelim{{Start: "/*", End: "*/"}},
+			StringDelimiters:             []string{"\"", "'"},
+			EscapeCharacter:              "\\",
+			LineContinuation:             "\\",
+			CommentBackslashContinuation: true,
+			RawStringPrefix:              `R"`,
+			DigitSeparator:               "'",
+			Trigraphs:                    true,
+		},
+		{
+			Name:             "Go",
+			Extensions:       []string{".go"},
+			LineComments:     []string{"//"},
+			BlockComments:    []CommentDelim{{Start: "/*", End: "*/"}},
+			StringDelimiters: []string{"\"", "'", "`"},
+			EscapeCharacter:  "\\",
+		},
+		{
+			Name:             "Python",
+			Extensions:       []string{".py"},
+			LineComments:     []string{"#"},
+			BlockComments:    []CommentDelim{{Start: `"""`, End: `"""`}, {Start: "'''", End: "'''"}},
+			StringDelimiters: []string{"\"", "'"},
+			EscapeCharacter:  "\\",
+			LineContinuation: "\\",
+		},
+		{
+			Name:             "Shell",
+			Extensions:       []string{".sh", ".bash"},
+			LineComments:     []string{"#"},
+			StringDelimiters: []string{"\"", "'"},
+			EscapeCharacter:  "\\",
+			LineContinuation: "\\",
+		},
+		{
+			Name:             "Make",
+			Filenames:        []string{"Makefile", "makefile", "GNUmakefile"},
+			Extensions:       []string{".mk"},
+			LineComments:     []string{"#"},
+			StringDelimiters: []string{"\"", "'"},
+			EscapeCharacter:  "\\",
+			LineContinuation: "\\",
+		},
+		{
+			Name:             "Dockerfile",
+			Filenames:        []string{"Dockerfile", "dockerfile"},
+			LineComments:     []string{"#"},
+			StringDelimiters: []string{"\"", "'"},
+			EscapeCharacter:  "\\",
+		},
+		{
+			Name:             "Ruby",
+			Extensions:       []string{".rb"},
+			LineComments:     []string{"#"},
+			BlockComments:    []CommentDelim{{Start: "=begin", End: "=end"}},
+			StringDelimiters: []string{"\"", "'"},
+			EscapeCharacter:  "\\",
+			LineContinuation: "\\",
+		},
+		{
+			Name:             "Lua",
+			Extensions:       []string{".lua"},
+			LineComments:     []string{"--"},
+			BlockComments:    []CommentDelim{{Start: "--[[", End: "]]"}},
+			StringDelimiters: []string{"\"", "'"},
+			EscapeCharacter:  "\\",
+		},
+		{
+			Name:             "HTML",
+			Extensions:       []string{".html", ".htm"},
+			BlockComments:    []CommentDelim{{Start: "<!--", End: "-->"}},
+			StringDelimiters: []string{"\"", "'"},
+			EscapeCharacter:  "\\",
+		},
+		{
+			Name:             "SQL",
+			Extensions:       []string{".sql"},
+			LineComments:     []string{"--"},
+			BlockComments:    []CommentDelim{{Start: "/*", End: "*/"}},
+			StringDelimiters: []string{"'"},
+			EscapeCharacter:  "\\",
+		},
+		{
+			Name:                "Haskell",
+			Extensions:          []string{".hs"},
+			LineComments:        []string{"--"},
+			BlockComments:       []CommentDelim{{Start: "{-", End: "-}"}},
+			NestedBlockComments: true,
+			StringDelimiters:    []string{"\"", "'"},
+			EscapeCharacter:     "\\",
+		},
+	}
+}
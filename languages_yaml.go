@@ -0,0 +1,239 @@
+package main
+
+import "strings"
+
+// This file implements just enough of YAML to load a language registry
+// config: nested mappings, sequences (block and inline "[a, b]" form), and
+// quoted or bare scalars. The repo has no third-party dependencies, so a
+// full YAML implementation would be overkill for this one config shape.
+
+// parseLanguagesYAML parses a YAML document shaped like the JSON language
+// config (a top-level list of language mappings) into []LanguageSpec.
+func parseLanguagesYAML(data []byte) ([]LanguageSpec, error) {
+	p := newYAMLParser(data)
+	root := p.parseBlock(0)
+
+	items, ok := root.([]interface{})
+	if !ok {
+		return nil, errInvalidYAML("expected a top-level list of languages")
+	}
+
+	specs := make([]LanguageSpec, 0, len(items))
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, errInvalidYAML("expected a mapping for each language entry")
+		}
+		specs = append(specs, languageSpecFromYAML(m))
+	}
+	return specs, nil
+}
+
+func errInvalidYAML(reason string) error {
+	return &yamlError{reason}
+}
+
+type yamlError struct{ reason string }
+
+func (e *yamlError) Error() string { return "invalid language config: " + e.reason }
+
+func languageSpecFromYAML(m map[string]interface{}) LanguageSpec {
+	spec := LanguageSpec{
+		Name:                         yamlString(m["name"]),
+		Extensions:                   yamlStringList(m["extensions"]),
+		Filenames:                    yamlStringList(m["filenames"]),
+		LineComments:                 yamlStringList(m["line_comments"]),
+		StringDelimiters:             yamlStringList(m["string_delimiters"]),
+		EscapeCharacter:              yamlString(m["escape_character"]),
+		LineContinuation:             yamlString(m["line_continuation"]),
+		NestedBlockComments:          yamlBool(m["nested_block_comments"]),
+		CommentBackslashContinuation: yamlBool(m["comment_backslash_continuation"]),
+		RawStringPrefix:              yamlString(m["raw_string_prefix"]),
+		DigitSeparator:               yamlString(m["digit_separator"]),
+		Trigraphs:                    yamlBool(m["trigraphs"]),
+	}
+
+	if raw, ok := m["block_comments"].([]interface{}); ok {
+		for _, entry := range raw {
+			if em, ok := entry.(map[string]interface{}); ok {
+				spec.BlockComments = append(spec.BlockComments, CommentDelim{
+					Start: yamlString(em["start"]),
+					End:   yamlString(em["end"]),
+				})
+			}
+		}
+	}
+
+	return spec
+}
+
+func yamlString(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+func yamlBool(v interface{}) bool {
+	s, _ := v.(string)
+	return s == "true" || s == "yes"
+}
+
+func yamlStringList(v interface{}) []string {
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// yamlParser walks a pre-cleaned slice of lines (comments and blank lines
+// stripped) and interprets indentation as block structure.
+type yamlParser struct {
+	lines []string
+	pos   int
+}
+
+func newYAMLParser(data []byte) *yamlParser {
+	raw := strings.Split(string(data), "\n")
+	lines := make([]string, 0, len(raw))
+	for _, l := range raw {
+		l = strings.TrimRight(l, " \t\r")
+		if idx := strings.Index(l, "#"); idx >= 0 && !strings.ContainsAny(l[:idx], "\"'") {
+			l = strings.TrimRight(l[:idx], " \t")
+		}
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		lines = append(lines, l)
+	}
+	return &yamlParser{lines: lines}
+}
+
+func indentOf(line string) int {
+	n := 0
+	for n < len(line) && line[n] == ' ' {
+		n++
+	}
+	return n
+}
+
+func (p *yamlParser) peek() (line string, indent int, ok bool) {
+	if p.pos >= len(p.lines) {
+		return "", 0, false
+	}
+	line = p.lines[p.pos]
+	return line, indentOf(line), true
+}
+
+// parseBlock parses whatever sits at indent: a sequence ("- ...") or a
+// mapping ("key: ..."), dispatching on the first line's shape.
+func (p *yamlParser) parseBlock(indent int) interface{} {
+	line, lineIndent, ok := p.peek()
+	if !ok || lineIndent < indent {
+		return nil
+	}
+	content := strings.TrimSpace(line)
+	if content == "-" || strings.HasPrefix(content, "- ") {
+		return p.parseSequence(lineIndent)
+	}
+	return p.parseMapping(lineIndent)
+}
+
+func (p *yamlParser) parseSequence(indent int) []interface{} {
+	var items []interface{}
+	for {
+		line, lineIndent, ok := p.peek()
+		if !ok || lineIndent != indent {
+			break
+		}
+		content := strings.TrimSpace(line)
+		if content != "-" && !strings.HasPrefix(content, "- ") {
+			break
+		}
+
+		rest := strings.TrimSpace(strings.TrimPrefix(content, "-"))
+		if rest == "" {
+			p.pos++
+			items = append(items, p.parseBlock(indent+1))
+			continue
+		}
+		if strings.Contains(rest, ":") {
+			// Rewrite "- key: value" as a mapping entry indented two past
+			// the dash, so any sibling "key: value" lines at that depth
+			// fold into the same map.
+			dashCol := strings.Index(line, "-")
+			p.lines[p.pos] = strings.Repeat(" ", dashCol+2) + rest
+			items = append(items, p.parseMapping(dashCol+2))
+			continue
+		}
+		p.pos++
+		items = append(items, yamlScalar(rest))
+	}
+	return items
+}
+
+func (p *yamlParser) parseMapping(indent int) map[string]interface{} {
+	m := map[string]interface{}{}
+	for {
+		line, lineIndent, ok := p.peek()
+		if !ok || lineIndent != indent {
+			break
+		}
+		content := strings.TrimSpace(line)
+		if content == "-" || strings.HasPrefix(content, "- ") {
+			break
+		}
+		idx := strings.Index(content, ":")
+		if idx < 0 {
+			break
+		}
+		key := strings.TrimSpace(content[:idx])
+		val := strings.TrimSpace(content[idx+1:])
+		p.pos++
+		switch {
+		case val == "":
+			m[key] = p.parseBlock(indent + 1)
+		case strings.HasPrefix(val, "["):
+			m[key] = yamlInlineList(val)
+		default:
+			m[key] = yamlScalar(val)
+		}
+	}
+	return m
+}
+
+func yamlScalar(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 {
+		quote := s[0]
+		if (quote == '"' || quote == '\'') && s[len(s)-1] == quote {
+			inner := s[1 : len(s)-1]
+			if quote == '"' {
+				inner = strings.ReplaceAll(inner, `\"`, `"`)
+				inner = strings.ReplaceAll(inner, `\\`, `\`)
+			}
+			return inner
+		}
+	}
+	return s
+}
+
+func yamlInlineList(s string) []interface{} {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, "[")
+	s = strings.TrimSuffix(s, "]")
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	parts := strings.Split(s, ",")
+	items := make([]interface{}, 0, len(parts))
+	for _, part := range parts {
+		items = append(items, yamlScalar(part))
+	}
+	return items
+}
@@ -2,263 +2,293 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
+	"runtime"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 )
 
 type FileStats struct {
 	TotalLines     int
+	Code           int
+	Blanks         int
 	InlineComments int
 	BlockComments  int
+	Language       string
 }
 
-type LanguageSpec struct {
-	inlineCommentStart string
-	blockCommentStart       string
-	blockCommentEnd         string
-	lineContinuation        string
-	escapeCharacter         string
-	specialCharsToEscape    []string
-	stringDelimiter         []string
-	fileExtensions          []string
+// Comments returns the cloc-style comment total: comment-only lines,
+// whether they were closed by an inline or a block delimiter.
+func (s FileStats) Comments() int {
+	return s.InlineComments + s.BlockComments
 }
 
-var languageSpecs = map[string]LanguageSpec{
-    "C/C++": {
-        inlineCommentStart: "//",
-        blockCommentStart:  "/*",
-        blockCommentEnd:    "*/",
-		lineContinuation:   "\\",
-		escapeCharacter:    "\\",
-		specialCharsToEscape: []string{"\\", "\"", "'", "a", "b", "f", "n", "r", "t", "v"},
-        stringDelimiter:    []string{"\"", "'"},
-        fileExtensions:     []string{".c", ".cpp", ".h", ".hpp"},
-    },
-    // Add more language specifications as needed
+// fileError pairs a path with the error encountered while analyzing it, so a
+// single bad file can be reported without aborting the rest of the run.
+type fileError struct {
+	path string
+	err  error
 }
 
-var activeLanguage LanguageSpec
+// sourceFile is a path paired with the LanguageSpec it was matched against,
+// resolved once by the walker so workers don't need the registry.
+type sourceFile struct {
+	path string
+	spec LanguageSpec
+}
+
+// fileResult carries one worker's analysis back to the collector.
+type fileResult struct {
+	path  string
+	stats FileStats
+}
 
 // Entry point of the program
 func main() {
-	configured_lang := os.Getenv("ACTIVE_LANGUAGE")
-	if configured_lang == "" {
-		configured_lang = "C/C++"
+	if len(os.Args) > 1 && os.Args[1] == "todos" {
+		os.Exit(runTodos(os.Args[2:]))
 	}
-	var ok bool
-	activeLanguage, ok  = languageSpecs[configured_lang]
-	if !ok {
-		fmt.Println("error: unsupported language: ", configured_lang)
-		return
-	}
-	args := os.Args[1:]
+
+	workers := flag.Int("workers", defaultWorkerCount(), "number of worker goroutines used to analyze files (default: $WORKERS or runtime.NumCPU())")
+	byLanguage := flag.Bool("by-language", false, "report totals grouped by language instead of per file")
+	flag.Bool("by-file", false, "report per-file statistics (default)")
+	langsPath := flag.String("langs", "", "path to a JSON or YAML language config, replacing the built-in language registry")
+
+	excludeDirs := stringSliceFlag{".git", "node_modules", "vendor", "build"}
+	flag.Var(&excludeDirs, "exclude-dir", "directory name or glob to prune from the walk (repeatable; defaults: .git, node_modules, vendor, build)")
+	var includes, excludes stringSliceFlag
+	flag.Var(&includes, "include", "glob a file's path or name must match to be analyzed (repeatable)")
+	flag.Var(&excludes, "exclude", "glob for files to skip, checked before -include (repeatable)")
+	followSymlinks := flag.Bool("follow-symlinks", false, "follow symlinked files and directories during the walk")
+	maxFileSize := flag.Int64("max-file-size", 0, "skip files larger than this many bytes (0 = no limit)")
+	format := flag.String("format", "text", "output format: text, json, xml, csv, or sarif")
+	output := flag.String("output", "", "file to write the report to (default: stdout)")
+	flag.Parse()
+
+	args := flag.Args()
 	if len(args) != 1 {
 		printHelp()
-	} else {
-		dir := args[0]
-		if err := countCommentLines(dir); err != nil {
+		return
+	}
+
+	registry := DefaultLanguageRegistry()
+	if *langsPath != "" {
+		var err error
+		registry, err = LoadLanguageRegistry(*langsPath)
+		if err != nil {
 			fmt.Println(err)
+			return
+		}
+	}
+
+	filter := fileFilter{
+		includes:       includes,
+		excludes:       excludes,
+		excludeDirs:    excludeDirs,
+		followSymlinks: *followSymlinks,
+		maxFileSize:    *maxFileSize,
+	}
+
+	reporter, err := newReporter(*format, *byLanguage)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	dir := args[0]
+	if err := countCommentLines(dir, registry, filter, reporter, *output, *workers); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// defaultWorkerCount picks the worker pool size: the WORKERS env var if set
+// to a valid positive integer, otherwise the number of logical CPUs.
+func defaultWorkerCount() int {
+	if v := os.Getenv("WORKERS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
 		}
 	}
+	return runtime.NumCPU()
 }
 
 // Prints the usage guide if the user inputs incorrectly
 func printHelp() {
-	fmt.Println("usage: \n\tgo run . <directory>")
+	fmt.Println("usage: \n\tgo run . [-workers N] [-by-language] [-langs file] [-include glob] [-exclude glob] [-exclude-dir name] [-follow-symlinks] [-max-file-size bytes] [-format text|json|xml|csv|sarif] [-output file] <directory>")
+	fmt.Println("\tgo run . todos [flags] <directory>")
 	fmt.Print()
 }
 
-// countCommentLines is the core logic to process each C/C++ file in the directory
-func countCommentLines(dir string) error {
+// countCommentLines is the core logic to process each source file in the
+// directory that matches a language in registry, after filter and any
+// .gitignore files have excluded the rest, writing the report via reporter
+// to outputPath (stdout if empty).
+func countCommentLines(dir string, registry LanguageRegistry, filter fileFilter, reporter Reporter, outputPath string, workers int) error {
 	// Verify that the directory exists
 	if _, err := os.Stat(dir); os.IsNotExist(err) {
 		return fmt.Errorf("error: directory does not exist: %s", dir)
 	}
 
-	// Walk the directory to collect all C/C++ source files
-	files, err := collectSourceFiles(dir)
-	if err != nil {
-		return err
+	if workers < 1 {
+		workers = 1
 	}
 
-	if len(files) == 0 {
-		return fmt.Errorf("error: no C/C++ source files found in directory: %s", dir)
-	}
+	// The walker is its own goroutine: it streams matching files into a
+	// buffered channel while the worker pool below starts consuming
+	// immediately, instead of waiting for the whole tree to be collected.
+	filesCh := make(chan sourceFile, 64)
+	walkErrCh := make(chan error, 1)
+	go func() {
+		walkErrCh <- walkFiles(dir, registry, filter, filesCh)
+	}()
 
-	// Process and print the comment line statistics for each file
-	stats, err := processFiles(files)
-	if err != nil {
-		return err
+	stats, fileErrs := processFiles(filesCh, workers)
+
+	if err := <-walkErrCh; err != nil {
+		return fmt.Errorf("error walking through directory: %s", err)
 	}
 
-	// Print the results in the required format
-	printResults(stats)
+	// Per-file errors are reported but never abort the run; totals are
+	// still printed for every file that succeeded.
+	for _, fe := range fileErrs {
+		fmt.Printf("error processing file %s: %v\n", fe.path, fe.err)
+	}
 
-	return nil
-}
+	if len(stats) == 0 {
+		return fmt.Errorf("error: no recognized source files found in directory: %s", dir)
+	}
 
-// collectSourceFiles walks through the directory and gathers all C/C++ files
-func collectSourceFiles(dir string) ([]string, error) {
-	files := []string{}
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+	w := os.Stdout
+	if outputPath != "" {
+		f, err := os.Create(outputPath)
 		if err != nil {
-			return err
-		}
-		if !info.IsDir() && isCSourceFile(info.Name()) {
-			files = append(files, path)
+			return fmt.Errorf("error creating output file %s: %w", outputPath, err)
 		}
-		return nil
-	})
-
-	if err != nil {
-		return nil, fmt.Errorf("error walking through directory: %s", err)
+		defer f.Close()
+		return reporter.Report(f, stats)
 	}
 
-	sort.Strings(files)
-	return files, nil
+	return reporter.Report(w, stats)
 }
 
-// isCSourceFile determines if the given file is a C or C++ source/header file
-func isCSourceFile(filename string) bool {
-	ext := strings.ToLower(filepath.Ext(filename))
-	for _, cExt := range activeLanguage.fileExtensions {
-		if ext == cExt {
-			return true
-		}
+// processFiles runs a pool of worker goroutines that consume files from
+// filesCh and analyze them concurrently, and collects the results (and any
+// per-file errors) as they complete.
+func processFiles(filesCh <-chan sourceFile, workers int) (map[string]FileStats, []fileError) {
+	resultsCh := make(chan fileResult)
+	errCh := make(chan fileError)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for sf := range filesCh {
+				stats, err := analyzeFile(sf.path, sf.spec)
+				if err != nil {
+					errCh <- fileError{path: sf.path, err: err}
+					continue
+				}
+				resultsCh <- fileResult{path: sf.path, stats: stats}
+			}
+		}()
 	}
-	return false
-}
 
-// processFiles calculates the comment line statistics for each file
-func processFiles(files []string) (map[string]FileStats, error) {
-	stats := make(map[string]FileStats)
+	// Close the result/error channels once every worker has finished, so
+	// the collector loop below knows when to stop.
+	go func() {
+		wg.Wait()
+		close(resultsCh)
+		close(errCh)
+	}()
 
-	for _, file := range files {
-		fileStats, err := analyzeFile(file)
-		if err != nil {
-			return nil, fmt.Errorf("error processing file %s: %v", file, err)
+	stats := make(map[string]FileStats)
+	var fileErrs []fileError
+	resultsOpen, errsOpen := true, true
+	for resultsOpen || errsOpen {
+		select {
+		case r, ok := <-resultsCh:
+			if !ok {
+				resultsOpen = false
+				continue
+			}
+			stats[r.path] = r.stats
+		case e, ok := <-errCh:
+			if !ok {
+				errsOpen = false
+				continue
+			}
+			fileErrs = append(fileErrs, e)
 		}
-		stats[file] = fileStats
 	}
 
-	return stats, nil
+	return stats, fileErrs
 }
 
-// analyzeFile reads a file and counts its total, inline, and block comment lines
-func analyzeFile(file string) (FileStats, error) {
+// analyzeFile reads a file and classifies every line as blank, code, or
+// comment (cloc-style): a line that contains any non-comment, non-whitespace
+// token is Code even if it also carries a trailing comment; a line that is
+// only whitespace is Blank; everything else is a comment-only line, recorded
+// as inline or block depending on which delimiter produced it. spec supplies
+// the language's comment/string/continuation tokens.
+func analyzeFile(file string, spec LanguageSpec) (FileStats, error) {
 	f, err := os.Open(file)
 	if err != nil {
 		return FileStats{}, err
 	}
 	defer f.Close()
 
-	stat := FileStats{}
-	isInBlockComment := false
-	isInInlineComment := false
-	isInString := false
-	currentStringDelimiter := ""
+	stat := FileStats{Language: spec.Name}
+	state := &scanState{}
 	scanner := bufio.NewScanner(f)
 
 	for scanner.Scan() {
-		line := scanner.Text()
+		line := applyTrigraphs(scanner.Text(), spec)
 		stat.TotalLines++
 
-		isCountedAsBlockCommment := false
 		lineContinued := false
-		if (len(line) > 0) {
-			lineContinued = line[len(line)-1] == activeLanguage.lineContinuation[0]
+		if spec.CommentBackslashContinuation && len(line) > 0 && spec.LineContinuation != "" {
+			lineContinued = line[len(line)-1] == spec.LineContinuation[0]
 		}
-			
-		i := 0
-		if line == "" {
-			if isInInlineComment {
-				stat.InlineComments++
-				isInInlineComment = false	
-			}
-			if isInBlockComment {
-				stat.BlockComments++
-			}
+
+		if strings.TrimSpace(line) == "" {
+			// A whitespace-only line can't end in a continuation, so any
+			// continued inline comment stops here; open blocks and strings
+			// carry on to the next line.
+			state.inInlineComment = false
+			stat.Blanks++
 			continue
 		}
-		for i < len(line) {
-			if isInString {
-				// Check if the string ends on this line
-				if strings.HasPrefix(line[i:], currentStringDelimiter) {
-					isInString = false
-					i += len(currentStringDelimiter)
-					currentStringDelimiter = ""
-					continue
-				}
 
-				// Check if the character is an escape character
-				if strings.HasPrefix(line[i:], activeLanguage.escapeCharacter) {
-					i += 2
-					continue
-				}
-			}
-
-			if isInBlockComment {
-				if !isCountedAsBlockCommment {
-					stat.BlockComments++
-					isCountedAsBlockCommment = true
-				}
-				// Check if the block comment ends on this line
-				if strings.HasPrefix(line[i:], activeLanguage.blockCommentEnd) {
-					isInBlockComment = false
-					i += len(activeLanguage.blockCommentEnd)
-					continue
-				}
-			}
+		segments, hasCode := scanLine(line, spec, state)
 
-			if isInInlineComment {
-				// The rest of the line is a comment
-				stat.InlineComments++
-				if !lineContinued {
-					isInInlineComment = false
-				}
-				break
-			}
-
-			// Check if the line is a string
-			start_string := false
-			for _, delimiter := range activeLanguage.stringDelimiter {
-				if strings.HasPrefix(line[i:], delimiter) && !isInBlockComment && !isInInlineComment && !isInString {
-					isInString = true
-					start_string = true
-					currentStringDelimiter = delimiter
-					i += len(delimiter)
-					break
-				}
-			}
-			if start_string {
-				continue
-			}
-			// Check if the line is a block comment
-			if strings.HasPrefix(line[i:], activeLanguage.blockCommentStart) && !isInString && !isInBlockComment && !isInInlineComment {
-				isInBlockComment = true
-				i += len(activeLanguage.blockCommentStart)
-				if !isCountedAsBlockCommment {
-					stat.BlockComments++
-					isCountedAsBlockCommment = true
-				}
-				continue
+		sawBlockComment, sawInlineComment := false, false
+		for _, seg := range segments {
+			if seg.Kind == blockCommentSegment {
+				sawBlockComment = true
+			} else {
+				sawInlineComment = true
 			}
+		}
 
-			// Check if the line is an inline comment
-			if strings.HasPrefix(line[i:], activeLanguage.inlineCommentStart) && !isInString && !isInBlockComment {
-				stat.InlineComments++
-				isInInlineComment = true
-				if !lineContinued {
-					isInInlineComment = false
-				}
-				break
-			}
+		if !lineContinued {
+			state.inInlineComment = false
+		}
 
-			// Move to the next character
-			i++
+		switch {
+		case hasCode:
+			stat.Code++
+		case sawBlockComment:
+			stat.BlockComments++
+		case sawInlineComment:
+			stat.InlineComments++
+		default:
+			stat.Blanks++
 		}
 	}
 
@@ -269,19 +299,73 @@ func analyzeFile(file string) (FileStats, error) {
 	return stat, nil
 }
 
-// printResults outputs the file statistics in the required format
-func printResults(stats map[string]FileStats) {
-	// Sort the file paths to ensure alphabetical output
+// cloAggregate accumulates cloc-style totals (files, code, blanks, comments)
+// across one or more FileStats.
+type cloAggregate struct {
+	Files    int
+	Code     int
+	Blanks   int
+	Comments int
+	Total    int
+}
+
+func (a *cloAggregate) add(stat FileStats) {
+	a.Files++
+	a.Code += stat.Code
+	a.Blanks += stat.Blanks
+	a.Comments += stat.Comments()
+	a.Total += stat.TotalLines
+}
+
+// printByFile prints one row per file plus a grand total.
+func printByFile(w io.Writer, stats map[string]FileStats) {
 	files := make([]string, 0, len(stats))
 	for file := range stats {
 		files = append(files, file)
 	}
 	sort.Strings(files)
 
-	// Print the results
+	fmt.Fprintf(w, "%-40s %8s %8s %8s %8s\n", "file", "code", "blanks", "comment", "total")
+	var grand cloAggregate
 	for _, file := range files {
 		stat := stats[file]
-		// Print formatted output with aligned columns
-		fmt.Printf("%-40s total: %4d    inline: %3d    block: %3d\n", file, stat.TotalLines, stat.InlineComments, stat.BlockComments)
+		fmt.Fprintf(w, "%-40s %8d %8d %8d %8d\n", file, stat.Code, stat.Blanks, stat.Comments(), stat.TotalLines)
+		grand.add(stat)
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 76))
+	fmt.Fprintf(w, "%-40s %8d %8d %8d %8d\n", "TOTAL", grand.Code, grand.Blanks, grand.Comments, grand.Total)
+}
+
+// printByLanguage aggregates every file's stats by its Language and prints
+// one row per language plus a grand total across all languages.
+func printByLanguage(w io.Writer, stats map[string]FileStats) {
+	byLang := make(map[string]*cloAggregate)
+	for _, stat := range stats {
+		agg, ok := byLang[stat.Language]
+		if !ok {
+			agg = &cloAggregate{}
+			byLang[stat.Language] = agg
+		}
+		agg.add(stat)
+	}
+
+	langs := make([]string, 0, len(byLang))
+	for lang := range byLang {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+
+	fmt.Fprintf(w, "%-20s %8s %8s %8s %8s %8s\n", "language", "files", "code", "blanks", "comment", "total")
+	var grand cloAggregate
+	for _, lang := range langs {
+		agg := byLang[lang]
+		fmt.Fprintf(w, "%-20s %8d %8d %8d %8d %8d\n", lang, agg.Files, agg.Code, agg.Blanks, agg.Comments, agg.Total)
+		grand.Files += agg.Files
+		grand.Code += agg.Code
+		grand.Blanks += agg.Blanks
+		grand.Comments += agg.Comments
+		grand.Total += agg.Total
 	}
+	fmt.Fprintln(w, strings.Repeat("-", 76))
+	fmt.Fprintf(w, "%-20s %8d %8d %8d %8d %8d\n", "TOTAL", grand.Files, grand.Code, grand.Blanks, grand.Comments, grand.Total)
 }
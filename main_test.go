@@ -0,0 +1,183 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func specFor(t *testing.T, filename string) LanguageSpec {
+	t.Helper()
+	spec, ok := DefaultLanguageRegistry().Lookup(filename)
+	if !ok {
+		t.Fatalf("no language registered for %q", filename)
+	}
+	return spec
+}
+
+func analyze(t *testing.T, spec LanguageSpec, content string) FileStats {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "source")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("writing test file: %v", err)
+	}
+	stats, err := analyzeFile(path, spec)
+	if err != nil {
+		t.Fatalf("analyzeFile: %v", err)
+	}
+	return stats
+}
+
+func TestAnalyzeFile(t *testing.T) {
+	tests := []struct {
+		name        string
+		filename    string
+		content     string
+		wantCode    int
+		wantInline  int
+		wantBlock   int
+		explanation string
+	}{
+		{
+			name:     "raw string literal hides comment-like content",
+			filename: "a.cpp",
+			content: "int a = 1;\n" +
+				"std::string s = R\"(line one\n" +
+				"// not a comment\n" +
+				"/* not a comment either */\n" +
+				")\";\n" +
+				"int b = 2;\n",
+			wantCode:    6,
+			explanation: "everything inside R\"(...)\" is string content, not a comment",
+		},
+		{
+			name:        "escaped quote inside char literal",
+			filename:    "a.c",
+			content:     "char q = '\\'';\nchar nl = '\\n';\nchar multi = 'ab';\n",
+			wantCode:    3,
+			explanation: "an escaped quote inside a char literal must not end the literal early",
+		},
+		{
+			name:        "digit separator is not a char literal",
+			filename:    "a.cpp",
+			content:     "int x = 1'000'000;\nint y = 2;\n",
+			wantCode:    2,
+			explanation: "a digit separator must not be mistaken for a char literal's opening quote",
+		},
+		{
+			name:        "trigraph line continuation extends comment",
+			filename:    "a.c",
+			content:     "// comment continues ??/\nstill comment\nint x = 1;\n",
+			wantCode:    1,
+			wantInline:  2,
+			explanation: "\"??/\" is a trigraph for \"\\\" and should continue the comment",
+		},
+		{
+			name:        "backslash continuation is language-specific",
+			filename:    "a.py",
+			content:     "# comment ends here \\\nnot a comment\n",
+			wantCode:    1,
+			wantInline:  1,
+			explanation: "Python's \"#\" comments aren't extended by a trailing backslash",
+		},
+		{
+			name:        "python triple-quoted string spans lines",
+			filename:    "a.py",
+			content:     "\"\"\"\ndocstring body\n\"\"\"\nx = 1\n",
+			wantCode:    1,
+			wantBlock:   3,
+			explanation: "a triple-quoted string spans multiple lines and must not be cut short by the single-quote string delimiter",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := specFor(t, tt.filename)
+			stats := analyze(t, spec, tt.content)
+			if stats.Code != tt.wantCode {
+				t.Errorf("Code = %d, want %d; %s", stats.Code, tt.wantCode, tt.explanation)
+			}
+			if stats.InlineComments != tt.wantInline {
+				t.Errorf("InlineComments = %d, want %d; %s", stats.InlineComments, tt.wantInline, tt.explanation)
+			}
+			if stats.BlockComments != tt.wantBlock {
+				t.Errorf("BlockComments = %d, want %d; %s", stats.BlockComments, tt.wantBlock, tt.explanation)
+			}
+		})
+	}
+}
+
+// wantAnnotation is the subset of an Annotation a TestExtractAnnotations
+// case checks.
+type wantAnnotation struct {
+	line int
+	tag  string
+	text string
+}
+
+func extractAnns(t *testing.T, spec LanguageSpec, content string) []*Annotation {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "source")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("writing test file: %v", err)
+	}
+	annotations, err := extractAnnotations(path, spec, buildTagPattern(defaultAnnotationTags()))
+	if err != nil {
+		t.Fatalf("extractAnnotations: %v", err)
+	}
+	return annotations
+}
+
+func TestExtractAnnotations(t *testing.T) {
+	tests := []struct {
+		name        string
+		filename    string
+		content     string
+		want        []wantAnnotation
+		explanation string
+	}{
+		{
+			name:     "nested block comment",
+			filename: "a.hs",
+			content: "{- outer\n" +
+				"  {- nested -}\n" +
+				"  TODO: after nested close\n" +
+				"-}\n",
+			want:        []wantAnnotation{{line: 3, tag: "TODO", text: "after nested close"}},
+			explanation: "the inner \"-}\" must not close the outer comment early and drop the TODO that follows it",
+		},
+		{
+			name:        "block comment followed by line comment on the same line",
+			filename:    "a.c",
+			content:     "/* TODO: a */ int x; // FIXME: b\n",
+			want:        []wantAnnotation{{line: 1, tag: "TODO", text: "a"}, {line: 1, tag: "FIXME", text: "b"}},
+			explanation: "a closed comment span must not leave a stale range for the second comment later on the same line to panic on",
+		},
+		{
+			name:     "closed block comment followed by an unterminated one on the same line",
+			filename: "a.c",
+			content: "/* done */ /* TODO: carries over\n" +
+				"still inside the second comment\n" +
+				"*/\n",
+			want:        []wantAnnotation{{line: 1, tag: "TODO", text: "carries over still inside the second comment"}},
+			explanation: "only the line's last (still-open) comment span may continue onto the next line",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := specFor(t, tt.filename)
+			annotations := extractAnns(t, spec, tt.content)
+			if len(annotations) != len(tt.want) {
+				t.Fatalf("got %d annotations, want %d; %s", len(annotations), len(tt.want), tt.explanation)
+			}
+			for i, want := range tt.want {
+				got := annotations[i]
+				if got.Line != want.line || got.Tag != want.tag || got.Text != want.text {
+					t.Errorf("annotation %d = {Line: %d, Tag: %q, Text: %q}, want {Line: %d, Tag: %q, Text: %q}; %s",
+						i, got.Line, got.Tag, got.Text, want.line, want.tag, want.text, tt.explanation)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// FileRecord is one file's statistics in a form suitable for structured
+// output formats.
+type FileRecord struct {
+	Path           string `json:"path" xml:"path"`
+	Language       string `json:"language" xml:"language"`
+	Total          int    `json:"total" xml:"total"`
+	Code           int    `json:"code" xml:"code"`
+	Blanks         int    `json:"blanks" xml:"blanks"`
+	InlineComments int    `json:"inline_comments" xml:"inline_comments"`
+	BlockComments  int    `json:"block_comments" xml:"block_comments"`
+}
+
+// Summary is the grand total across every file in a report.
+type Summary struct {
+	Files    int `json:"files" xml:"files"`
+	Total    int `json:"total" xml:"total"`
+	Code     int `json:"code" xml:"code"`
+	Blanks   int `json:"blanks" xml:"blanks"`
+	Comments int `json:"comments" xml:"comments"`
+}
+
+// Report is the structured form of a run's results: a sorted list of
+// per-file records plus their aggregate summary.
+type Report struct {
+	XMLName xml.Name     `json:"-" xml:"report"`
+	Files   []FileRecord `json:"files" xml:"files>file"`
+	Summary Summary      `json:"summary" xml:"summary"`
+}
+
+// buildReport converts the raw stats map into a Report, sorted by path.
+func buildReport(stats map[string]FileStats) Report {
+	paths := make([]string, 0, len(stats))
+	for path := range stats {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	report := Report{Files: make([]FileRecord, 0, len(paths))}
+	for _, path := range paths {
+		stat := stats[path]
+		report.Files = append(report.Files, FileRecord{
+			Path:           path,
+			Language:       stat.Language,
+			Total:          stat.TotalLines,
+			Code:           stat.Code,
+			Blanks:         stat.Blanks,
+			InlineComments: stat.InlineComments,
+			BlockComments:  stat.BlockComments,
+		})
+		report.Summary.Files++
+		report.Summary.Total += stat.TotalLines
+		report.Summary.Code += stat.Code
+		report.Summary.Blanks += stat.Blanks
+		report.Summary.Comments += stat.Comments()
+	}
+	return report
+}
+
+// Reporter writes a run's results to w in some output format.
+type Reporter interface {
+	Report(w io.Writer, stats map[string]FileStats) error
+}
+
+// newReporter resolves the -format flag to a Reporter. byLanguage only
+// affects the text reporter; the structured formats always emit full
+// per-file records plus a summary, since that's what a CI consumer wants.
+func newReporter(format string, byLanguage bool) (Reporter, error) {
+	switch format {
+	case "", "text":
+		return textReporter{byLanguage: byLanguage}, nil
+	case "json":
+		return jsonReporter{}, nil
+	case "xml":
+		return xmlReporter{}, nil
+	case "csv":
+		return csvReporter{}, nil
+	case "sarif":
+		return sarifReporter{}, nil
+	default:
+		return nil, fmt.Errorf("error: unsupported -format %q (use text, json, xml, csv, or sarif)", format)
+	}
+}
+
+type textReporter struct {
+	byLanguage bool
+}
+
+func (r textReporter) Report(w io.Writer, stats map[string]FileStats) error {
+	if r.byLanguage {
+		printByLanguage(w, stats)
+	} else {
+		printByFile(w, stats)
+	}
+	return nil
+}
+
+type jsonReporter struct{}
+
+func (jsonReporter) Report(w io.Writer, stats map[string]FileStats) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(buildReport(stats))
+}
+
+type xmlReporter struct{}
+
+func (xmlReporter) Report(w io.Writer, stats map[string]FileStats) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(buildReport(stats)); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+type csvReporter struct{}
+
+func (csvReporter) Report(w io.Writer, stats map[string]FileStats) error {
+	report := buildReport(stats)
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"path", "language", "total", "code", "blanks", "inline_comments", "block_comments"}); err != nil {
+		return err
+	}
+	for _, f := range report.Files {
+		record := []string{
+			f.Path,
+			f.Language,
+			fmt.Sprint(f.Total),
+			fmt.Sprint(f.Code),
+			fmt.Sprint(f.Blanks),
+			fmt.Sprint(f.InlineComments),
+			fmt.Sprint(f.BlockComments),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// sarifRun/sarifResult/sarifLocation are a minimal subset of the SARIF
+// 2.1.0 schema: one "note"-level result per file, carrying the same counts
+// as the other structured formats, so this tool's output can be consumed by
+// SARIF-aware CI tooling.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name string `json:"name"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifReporter struct{}
+
+func (sarifReporter) Report(w io.Writer, stats map[string]FileStats) error {
+	report := buildReport(stats)
+
+	run := sarifRun{Tool: sarifTool{Driver: sarifDriver{Name: "commentcounter"}}}
+	for _, f := range report.Files {
+		run.Results = append(run.Results, sarifResult{
+			RuleID: "line-count",
+			Level:  "note",
+			Message: sarifMessage{
+				Text: fmt.Sprintf("%s: code=%d blanks=%d comments=%d total=%d", f.Language, f.Code, f.Blanks, f.InlineComments+f.BlockComments, f.Total),
+			},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: f.Path}}}},
+		})
+	}
+
+	log := sarifLog{
+		Schema:  "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log)
+}
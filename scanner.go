@@ -0,0 +1,243 @@
+package main
+
+import "strings"
+
+// trigraphReplacer translates legacy C trigraph sequences to the character
+// they stand for, so e.g. a line ending in "??/" is seen as a line
+// continuation just like one ending in "\".
+var trigraphReplacer = strings.NewReplacer(
+	"??=", "#", "??/", "\\", "??'", "^", "??(", "[",
+	"??)", "]", "??!", "|", "??<", "{", "??>", "}", "??-", "~",
+)
+
+// applyTrigraphs returns line with spec's trigraph sequences replaced,
+// unchanged if spec.Trigraphs is false.
+func applyTrigraphs(line string, spec LanguageSpec) string {
+	if !spec.Trigraphs {
+		return line
+	}
+	return trigraphReplacer.Replace(line)
+}
+
+// isDigitByte reports whether b is an ASCII decimal digit.
+func isDigitByte(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+// isDigitSeparator reports whether the byte at line[i] is spec's digit-group
+// separator (e.g. C++14's "1'000'000") rather than the start of a char or
+// string literal that happens to share the same character.
+func isDigitSeparator(line string, i int, spec LanguageSpec) bool {
+	if spec.DigitSeparator == "" || !strings.HasPrefix(line[i:], spec.DigitSeparator) {
+		return false
+	}
+	sep := len(spec.DigitSeparator)
+	return i > 0 && isDigitByte(line[i-1]) && i+sep < len(line) && isDigitByte(line[i+sep])
+}
+
+// commentSegmentKind distinguishes the two kinds of comment span scanLine
+// can report.
+type commentSegmentKind int
+
+const (
+	blockCommentSegment commentSegmentKind = iota
+	inlineCommentSegment
+)
+
+// commentSegment marks the byte range [Start, End) of a scanned line that is
+// the body of one comment — the text between its delimiters, excluding the
+// delimiters themselves.
+type commentSegment struct {
+	Kind  commentSegmentKind
+	Start int
+	End   int
+}
+
+// scanState is a tokenizer's memory that carries across lines: whether a
+// string, raw string, or comment opened on an earlier line is still open,
+// and which delimiter is closing it. One scanState belongs to exactly one
+// file scan; analyzeFile and extractAnnotations each create their own and
+// feed it to scanLine one line at a time.
+type scanState struct {
+	inBlockComment     bool
+	inInlineComment    bool
+	inString           bool
+	stringDelim        string
+	activeBlockComment CommentDelim
+	blockDepth         int
+	inRawString        bool
+	rawStringCloser    string
+}
+
+// scanLine tokenizes one line of source according to spec, advancing state
+// across the line's strings and comments, and reports every comment segment
+// found on the line plus whether the line contained any non-comment,
+// non-whitespace token ("code"). line is assumed to already have had
+// spec's trigraphs applied (see applyTrigraphs); a line may contain more
+// than one comment segment (e.g. a closed block comment followed by a line
+// comment), and the last segment, if still open at end of line, is the one
+// that carries into the next call.
+//
+// analyzeFile and extractAnnotations both drive this state machine: the
+// former only cares whether any code or comment was seen per line, the
+// latter needs each segment's exact text to match annotation tags against.
+// Sharing it here means a tokenizer fix (e.g. nested block comment depth)
+// only has to be made once.
+func scanLine(line string, spec LanguageSpec, state *scanState) (segments []commentSegment, hasCode bool) {
+	segStart := -1
+	var segKind commentSegmentKind
+	flush := func(end int) {
+		if segStart >= 0 {
+			segments = append(segments, commentSegment{Kind: segKind, Start: segStart, End: end})
+			segStart = -1
+		}
+	}
+
+	i := 0
+	for i < len(line) {
+		if state.inRawString {
+			hasCode = true
+			if strings.HasPrefix(line[i:], state.rawStringCloser) {
+				state.inRawString = false
+				i += len(state.rawStringCloser)
+				continue
+			}
+			i++
+			continue
+		}
+
+		if state.inString {
+			hasCode = true
+			if strings.HasPrefix(line[i:], state.stringDelim) {
+				state.inString = false
+				i += len(state.stringDelim)
+				continue
+			}
+			if spec.EscapeCharacter != "" && strings.HasPrefix(line[i:], spec.EscapeCharacter) {
+				i += 2
+				continue
+			}
+			i++
+			continue
+		}
+
+		if state.inBlockComment {
+			if segStart < 0 {
+				segStart = i
+				segKind = blockCommentSegment
+			}
+			if spec.NestedBlockComments && strings.HasPrefix(line[i:], state.activeBlockComment.Start) {
+				state.blockDepth++
+				i += len(state.activeBlockComment.Start)
+				continue
+			}
+			if strings.HasPrefix(line[i:], state.activeBlockComment.End) {
+				state.blockDepth--
+				i += len(state.activeBlockComment.End)
+				if state.blockDepth <= 0 {
+					flush(i - len(state.activeBlockComment.End))
+					state.inBlockComment = false
+				}
+				continue
+			}
+			i++
+			continue
+		}
+
+		if state.inInlineComment {
+			if segStart < 0 {
+				segStart = i
+				segKind = inlineCommentSegment
+			}
+			i = len(line)
+			continue
+		}
+
+		if line[i] == ' ' || line[i] == '\t' {
+			i++
+			continue
+		}
+
+		if isDigitSeparator(line, i, spec) {
+			hasCode = true
+			i += len(spec.DigitSeparator)
+			continue
+		}
+
+		// Check if the line starts a raw string literal, e.g. C++11's
+		// R"delim(...)delim" — the delimiter is whatever precedes "(".
+		if spec.RawStringPrefix != "" && strings.HasPrefix(line[i:], spec.RawStringPrefix) {
+			if open := strings.IndexByte(line[i+len(spec.RawStringPrefix):], '('); open >= 0 {
+				delim := line[i+len(spec.RawStringPrefix) : i+len(spec.RawStringPrefix)+open]
+				state.inRawString = true
+				hasCode = true
+				state.rawStringCloser = ")" + delim + `"`
+				i += len(spec.RawStringPrefix) + open + 1
+				continue
+			}
+		}
+
+		// Block comment delimiters are checked before string delimiters so
+		// a multi-character delimiter that happens to start with a string
+		// delimiter's character (e.g. Python's `"""` vs `"`) wins the
+		// match.
+		matchedBlock := false
+		for _, delim := range spec.BlockComments {
+			if strings.HasPrefix(line[i:], delim.Start) {
+				state.inBlockComment = true
+				state.activeBlockComment = delim
+				state.blockDepth = 1
+				i += len(delim.Start)
+				segStart = i
+				segKind = blockCommentSegment
+				matchedBlock = true
+				break
+			}
+		}
+		if matchedBlock {
+			continue
+		}
+
+		matchedString := false
+		for _, delimiter := range spec.StringDelimiters {
+			if strings.HasPrefix(line[i:], delimiter) {
+				state.inString = true
+				hasCode = true
+				state.stringDelim = delimiter
+				matchedString = true
+				i += len(delimiter)
+				break
+			}
+		}
+		if matchedString {
+			continue
+		}
+
+		matchedLine := false
+		for _, prefix := range spec.LineComments {
+			if strings.HasPrefix(line[i:], prefix) {
+				state.inInlineComment = true
+				i += len(prefix)
+				segStart = i
+				segKind = inlineCommentSegment
+				matchedLine = true
+				break
+			}
+		}
+		if matchedLine {
+			continue
+		}
+
+		hasCode = true
+		i++
+	}
+
+	if state.inInlineComment {
+		flush(len(line))
+	}
+	if state.inBlockComment {
+		flush(len(line))
+	}
+
+	return segments, hasCode
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Annotation is one recognized TODO/FIXME/... comment.
+type Annotation struct {
+	Path   string `json:"path"`
+	Line   int    `json:"line"`
+	Tag    string `json:"tag"`
+	Author string `json:"author,omitempty"`
+	Text   string `json:"text"`
+}
+
+// defaultAnnotationTags are the tags recognized even if -tag adds no more.
+func defaultAnnotationTags() []string {
+	return []string{"TODO", "FIXME", "XXX", "HACK", "NOTE"}
+}
+
+// buildTagPattern compiles a regexp that matches a comment body starting
+// with one of tags, optionally followed by "(author)" and/or a colon, e.g.
+// "TODO(alice): refactor this" or "FIXME fix the race".
+func buildTagPattern(tags []string) *regexp.Regexp {
+	escaped := make([]string, len(tags))
+	for i, tag := range tags {
+		escaped[i] = regexp.QuoteMeta(tag)
+	}
+	pattern := fmt.Sprintf(`^(%s)(\(([^)]+)\))?:?\s*(.*)$`, strings.Join(escaped, "|"))
+	return regexp.MustCompile(pattern)
+}
+
+// runTodos implements the "todos" subcommand: it walks a directory exactly
+// like the main comment counter, but extracts annotation comments instead
+// of tallying lines. It returns the process exit code.
+func runTodos(args []string) int {
+	fs := flag.NewFlagSet("todos", flag.ExitOnError)
+	workers := fs.Int("workers", defaultWorkerCount(), "number of worker goroutines used to scan files")
+	langsPath := fs.String("langs", "", "path to a JSON or YAML language config, replacing the built-in language registry")
+	format := fs.String("format", "text", "output format: text or json")
+	failOn := fs.String("fail-on", "", "comma-separated tags (e.g. TODO,FIXME) that cause a non-zero exit if any are found")
+	var extraTags stringSliceFlag
+	fs.Var(&extraTags, "tag", "additional annotation tag to recognize, beyond TODO/FIXME/XXX/HACK/NOTE (repeatable)")
+
+	excludeDirs := stringSliceFlag{".git", "node_modules", "vendor", "build"}
+	fs.Var(&excludeDirs, "exclude-dir", "directory name or glob to prune from the walk (repeatable)")
+	var includes, excludes stringSliceFlag
+	fs.Var(&includes, "include", "glob a file's path or name must match to be scanned (repeatable)")
+	fs.Var(&excludes, "exclude", "glob for files to skip, checked before -include (repeatable)")
+	followSymlinks := fs.Bool("follow-symlinks", false, "follow symlinked files and directories during the walk")
+	maxFileSize := fs.Int64("max-file-size", 0, "skip files larger than this many bytes (0 = no limit)")
+
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Println("usage: \n\tgo run . todos [flags] <directory>")
+		return 1
+	}
+	dir := fs.Arg(0)
+
+	registry := DefaultLanguageRegistry()
+	if *langsPath != "" {
+		var err error
+		registry, err = LoadLanguageRegistry(*langsPath)
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+	}
+
+	filter := fileFilter{
+		includes:       includes,
+		excludes:       excludes,
+		excludeDirs:    excludeDirs,
+		followSymlinks: *followSymlinks,
+		maxFileSize:    *maxFileSize,
+	}
+
+	tagPattern := buildTagPattern(append(defaultAnnotationTags(), extraTags...))
+
+	filesCh := make(chan sourceFile, 64)
+	walkErrCh := make(chan error, 1)
+	go func() {
+		walkErrCh <- walkFiles(dir, registry, filter, filesCh)
+	}()
+
+	annotations, fileErrs := collectAnnotations(filesCh, *workers, tagPattern)
+
+	if err := <-walkErrCh; err != nil {
+		fmt.Printf("error walking through directory: %s\n", err)
+		return 1
+	}
+	for _, fe := range fileErrs {
+		fmt.Printf("error processing file %s: %v\n", fe.path, fe.err)
+	}
+
+	sort.Slice(annotations, func(i, j int) bool {
+		if annotations[i].Path != annotations[j].Path {
+			return annotations[i].Path < annotations[j].Path
+		}
+		return annotations[i].Line < annotations[j].Line
+	})
+
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(annotations)
+	default:
+		for _, a := range annotations {
+			if a.Author != "" {
+				fmt.Printf("%s:%d: %s(%s): %s\n", a.Path, a.Line, a.Tag, a.Author, a.Text)
+			} else {
+				fmt.Printf("%s:%d: %s: %s\n", a.Path, a.Line, a.Tag, a.Text)
+			}
+		}
+	}
+
+	if *failOn != "" {
+		failTags := map[string]bool{}
+		for _, t := range strings.Split(*failOn, ",") {
+			failTags[strings.ToUpper(strings.TrimSpace(t))] = true
+		}
+		for _, a := range annotations {
+			if failTags[strings.ToUpper(a.Tag)] {
+				return 1
+			}
+		}
+	}
+
+	return 0
+}
+
+// annotationResult carries one worker's extracted annotations back to the
+// collector, mirroring fileResult for the counting pipeline.
+type annotationResult struct {
+	annotations []*Annotation
+}
+
+// collectAnnotations runs the same producer/consumer shape as processFiles,
+// extracting annotations from each file instead of tallying lines.
+func collectAnnotations(filesCh <-chan sourceFile, workers int, tagPattern *regexp.Regexp) ([]*Annotation, []fileError) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	resultsCh := make(chan annotationResult)
+	errCh := make(chan fileError)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for sf := range filesCh {
+				anns, err := extractAnnotations(sf.path, sf.spec, tagPattern)
+				if err != nil {
+					errCh <- fileError{path: sf.path, err: err}
+					continue
+				}
+				resultsCh <- annotationResult{annotations: anns}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(resultsCh)
+		close(errCh)
+	}()
+
+	var all []*Annotation
+	var fileErrs []fileError
+	resultsOpen, errsOpen := true, true
+	for resultsOpen || errsOpen {
+		select {
+		case r, ok := <-resultsCh:
+			if !ok {
+				resultsOpen = false
+				continue
+			}
+			all = append(all, r.annotations...)
+		case e, ok := <-errCh:
+			if !ok {
+				errsOpen = false
+				continue
+			}
+			fileErrs = append(fileErrs, e)
+		}
+	}
+
+	return all, fileErrs
+}
+
+// extractAnnotations tokenizes file the same way analyzeFile does (sharing
+// its scanLine state machine), but instead of classifying lines it pulls
+// out the text of every comment segment and matches it against tagPattern.
+// A line can carry more than one comment (e.g. a closed block comment
+// followed by a line comment); each is matched independently, in order. A
+// match that starts a block comment keeps accumulating the block's
+// following lines into the same annotation's Text until the block ends, a
+// blank comment line is hit, or another tag starts.
+func extractAnnotations(file string, spec LanguageSpec, tagPattern *regexp.Regexp) ([]*Annotation, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var annotations []*Annotation
+	var current *Annotation
+	state := &scanState{}
+
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := applyTrigraphs(scanner.Text(), spec)
+
+		wasContinuingBlock := state.inBlockComment
+		segments, _ := scanLine(line, spec, state)
+
+		// Inline comments never carry over to the next line for annotation
+		// purposes; only block comments do.
+		state.inInlineComment = false
+
+		if len(segments) == 0 {
+			current = nil
+			continue
+		}
+
+		for idx, seg := range segments {
+			text := strings.TrimSpace(line[seg.Start:seg.End])
+			text = strings.TrimLeft(text, "*-#=~ \t")
+
+			if idx == 0 && wasContinuingBlock && current != nil {
+				if text == "" {
+					current = nil
+					continue
+				}
+				if m := tagPattern.FindStringSubmatch(text); m != nil {
+					current = newAnnotation(&annotations, file, lineNo, m)
+					continue
+				}
+				current.Text = strings.TrimSpace(current.Text + " " + text)
+				continue
+			}
+
+			if m := tagPattern.FindStringSubmatch(text); m != nil {
+				current = newAnnotation(&annotations, file, lineNo, m)
+			} else {
+				current = nil
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return annotations, nil
+}
+
+// newAnnotation appends a new Annotation built from a tagPattern match and
+// returns a stable pointer to it for later lines to keep extending.
+func newAnnotation(annotations *[]*Annotation, file string, lineNo int, m []string) *Annotation {
+	ann := &Annotation{
+		Path:   file,
+		Line:   lineNo,
+		Tag:    m[1],
+		Author: m[3],
+		Text:   strings.TrimSpace(m[4]),
+	}
+	*annotations = append(*annotations, ann)
+	return ann
+}